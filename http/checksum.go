@@ -0,0 +1,156 @@
+package http
+
+import (
+	"container/list"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/filebrowser/filebrowser/types"
+)
+
+// checksumCacheSize bounds the number of digests kept in memory. Each
+// entry is tiny (a hex string keyed by path/mtime/size/algo), so this
+// is generous without being wasteful.
+const checksumCacheSize = 512
+
+type checksumKey struct {
+	scope string
+	path  string
+	mtime int64
+	size  int64
+	algo  string
+}
+
+// checksumCache is a small LRU cache of computed digests, keyed by the
+// user's scope, the file's path, mtime and size so a digest is reused
+// only while the file it was computed from is unchanged and so two
+// users can never read back each other's digest for a same-named,
+// same-size, same-mtime file in a different scope.
+type checksumCache struct {
+	mu    sync.Mutex
+	cap   int
+	ll    *list.List
+	items map[checksumKey]*list.Element
+}
+
+type checksumEntry struct {
+	key   checksumKey
+	value string
+}
+
+func newChecksumCache(cap int) *checksumCache {
+	return &checksumCache{
+		cap:   cap,
+		ll:    list.New(),
+		items: map[checksumKey]*list.Element{},
+	}
+}
+
+func (c *checksumCache) Get(key checksumKey) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return "", false
+	}
+
+	c.ll.MoveToFront(el)
+	return el.Value.(*checksumEntry).value, true
+}
+
+func (c *checksumCache) Add(key checksumKey, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*checksumEntry).value = value
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	el := c.ll.PushFront(&checksumEntry{key: key, value: value})
+	c.items[key] = el
+
+	if c.ll.Len() > c.cap {
+		oldest := c.ll.Back()
+		if oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.items, oldest.Value.(*checksumEntry).key)
+		}
+	}
+}
+
+var checksumLRU = newChecksumCache(checksumCacheSize)
+
+func newChecksumHasher(algo string) (hash.Hash, error) {
+	switch algo {
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "blake2b":
+		return blake2b.New256(nil)
+	default:
+		return nil, types.ErrInvalidOption
+	}
+}
+
+// fileChecksums computes every requested digest of f in a single
+// streaming pass, reusing cached digests for algorithms already known
+// for this exact (scope, path, mtime, size) combination. scope should
+// identify the filesystem root the path is relative to (user.Scope),
+// so two users' scopes never collide in the shared cache.
+func fileChecksums(f io.Reader, info os.FileInfo, scope, path string, algos []string) (map[string]string, error) {
+	result := map[string]string{}
+	hashers := map[string]hash.Hash{}
+
+	for _, algo := range algos {
+		algo = strings.TrimSpace(algo)
+		if algo == "" {
+			continue
+		}
+
+		key := checksumKey{scope: scope, path: path, mtime: info.ModTime().UnixNano(), size: info.Size(), algo: algo}
+		if cached, ok := checksumLRU.Get(key); ok {
+			result[algo] = cached
+			continue
+		}
+
+		h, err := newChecksumHasher(algo)
+		if err != nil {
+			return nil, err
+		}
+		hashers[algo] = h
+	}
+
+	if len(hashers) > 0 {
+		writers := make([]io.Writer, 0, len(hashers))
+		for _, h := range hashers {
+			writers = append(writers, h)
+		}
+
+		if _, err := io.Copy(io.MultiWriter(writers...), f); err != nil {
+			return nil, err
+		}
+
+		for algo, h := range hashers {
+			sum := hex.EncodeToString(h.Sum(nil))
+			result[algo] = sum
+			checksumLRU.Add(checksumKey{scope: scope, path: path, mtime: info.ModTime().UnixNano(), size: info.Size(), algo: algo}, sum)
+		}
+	}
+
+	return result, nil
+}
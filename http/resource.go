@@ -1,13 +1,18 @@
 package http
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/spf13/afero"
 
 	"github.com/filebrowser/filebrowser/types"
 )
@@ -29,6 +34,30 @@ func httpFsErr(err error) int {
 	}
 }
 
+// fileEtag computes the ETag a client should present via If-Match to
+// safely overwrite a file it has already fetched.
+func fileEtag(info os.FileInfo) string {
+	return fmt.Sprintf(`"%x%x"`, info.ModTime().UnixNano(), info.Size())
+}
+
+// resourceConflict is the body returned on a 409 so the frontend can
+// prompt the user to rename, overwrite, or diff against what's there.
+type resourceConflict struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+func writeResourceConflict(w http.ResponseWriter, path string, info os.FileInfo) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(&resourceConflict{
+		Path:     path,
+		Size:     info.Size(),
+		Modified: info.ModTime(),
+	})
+}
+
 func (e *Env) getResourceData(w http.ResponseWriter, r *http.Request, prefix string) (string, *types.User, bool) {
 	user, ok := e.getUser(w, r)
 	if !ok {
@@ -84,8 +113,34 @@ func (e *Env) resourceGetHandler(w http.ResponseWriter, r *http.Request) {
 		file.Type = "textImmutable"
 	}
 
-	if checksum := r.URL.Query().Get("checksum"); checksum != "" {
-		err = file.Checksum(checksum)
+	checksumParam := r.URL.Query().Get("checksum")
+	// download=true is how a plain download is distinguished from the
+	// metadata fetch the UI does to render a file's preview/listing
+	// entry; only a plain download or an explicit ?checksum= request pays
+	// for hashing the file.
+	download := r.URL.Query().Get("download") == "true"
+
+	if checksumParam != "" || download {
+		algos := []string{"sha256"}
+		if checksumParam != "" {
+			algos = strings.Split(checksumParam, ",")
+		}
+
+		f, err := user.Fs.Open(path)
+		if err != nil {
+			httpErr(w, httpFsErr(err), err)
+			return
+		}
+
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			httpErr(w, httpFsErr(err), err)
+			return
+		}
+
+		sums, err := fileChecksums(f, info, user.Scope, path, algos)
+		f.Close()
 		if err == types.ErrInvalidOption {
 			httpErr(w, http.StatusBadRequest, nil)
 			return
@@ -94,8 +149,20 @@ func (e *Env) resourceGetHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// do not waste bandwidth if we just want the checksum
-		file.Content = ""
+		for algo, sum := range sums {
+			w.Header().Set("X-Checksum-"+algo, sum)
+		}
+
+		if checksumParam != "" {
+			// do not waste bandwidth if we just want the checksum
+			file.Content = ""
+
+			renderJSON(w, struct {
+				*types.FileInfo
+				Checksums map[string]string `json:"checksums"`
+			}{file, sums})
+			return
+		}
 	}
 
 	renderJSON(w, file)
@@ -156,9 +223,28 @@ func (e *Env) resourcePostPutHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if r.Method == http.MethodPost && r.URL.Query().Get("override") != "true" {
-		if _, err := user.Fs.Stat(path); err == nil {
-			httpErr(w, http.StatusConflict, nil)
+	stat, statErr := user.Fs.Stat(path)
+	exists := statErr == nil
+
+	if r.Method == http.MethodPost && exists && r.URL.Query().Get("override") != "true" {
+		writeResourceConflict(w, path, stat)
+		return
+	}
+
+	if r.Method == http.MethodPut && exists {
+		ifMatch := r.Header.Get("If-Match")
+		override := r.URL.Query().Get("override") == "true"
+
+		switch {
+		case ifMatch != "":
+			if ifMatch != fileEtag(stat) {
+				httpErr(w, http.StatusPreconditionFailed, nil)
+				return
+			}
+		case override:
+			// Explicit opt-in to overwrite when the client has no prior ETag.
+		default:
+			writeResourceConflict(w, path, stat)
 			return
 		}
 	}
@@ -181,8 +267,7 @@ func (e *Env) resourcePostPutHandler(w http.ResponseWriter, r *http.Request) {
 			return err
 		}
 
-		etag := fmt.Sprintf(`"%x%x"`, info.ModTime().UnixNano(), info.Size())
-		w.Header().Set("ETag", etag)
+		w.Header().Set("ETag", fileEtag(info))
 		return nil
 	}, "upload", path, "", user)
 
@@ -229,18 +314,100 @@ func (e *Env) resourcePatchHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	override := r.URL.Query().Get("override") == "true"
+	if action == "copy" && !override {
+		if _, err := user.Fs.Stat(dst); err == nil {
+			httpErr(w, http.StatusConflict, nil)
+			return
+		}
+	}
+
 	err = e.Runner.Run(func() error {
 		if action == "copy" {
-			// TODO: err = user.FileSystem.Copy(src, dst)
-			return nil
+			return copyResource(user.Fs, src, dst)
 		}
 
 		return user.Fs.Rename(src, dst)
-	}, "action", src, dst, user)
+	}, action, src, dst, user)
 
 	httpErr(w, httpFsErr(err), err)
 }
 
+// copyResource copies src to dst on fs. Regular files are streamed
+// through OpenFile preserving mode and mtime; directories are walked
+// and recreated at dst, copying each file in turn. If a later file in a
+// directory copy fails, the partially written destination is removed on
+// a best-effort basis.
+func copyResource(fs afero.Fs, src, dst string) error {
+	info, err := fs.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		if err := copyFile(fs, src, dst, info); err != nil {
+			fs.Remove(dst)
+			return err
+		}
+		return nil
+	}
+
+	err = afero.Walk(fs, src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, rel)
+		if fi.IsDir() {
+			return fs.MkdirAll(target, fi.Mode())
+		}
+
+		return copyFile(fs, path, target, fi)
+	})
+
+	if err != nil {
+		fs.RemoveAll(dst)
+		return err
+	}
+
+	return nil
+}
+
+// copyFile copies a single file from src to dst on fs, preserving mode
+// and mtime.
+func copyFile(fs afero.Fs, src, dst string, info os.FileInfo) error {
+	in, err := fs.OpenFile(src, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := fs.MkdirAll(filepath.Dir(dst), 0775); err != nil {
+		return err
+	}
+
+	out, err := fs.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return fs.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
 func handleSortOrder(w http.ResponseWriter, r *http.Request, scope string) (sort string, order string, err error) {
 	sort = r.URL.Query().Get("sort")
 	order = r.URL.Query().Get("order")
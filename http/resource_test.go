@@ -0,0 +1,129 @@
+package http
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func TestCopyFile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	afero.WriteFile(fs, "/src/file.txt", []byte("hello"), 0664)
+	info, err := fs.Stat("/src/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := copyFile(fs, "/src/file.txt", "/dst/file.txt", info); err != nil {
+		t.Fatalf("copyFile() error = %v", err)
+	}
+
+	got, err := afero.ReadFile(fs, "/dst/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello" {
+		t.Fatalf("copied content = %q, want %q", got, "hello")
+	}
+}
+
+func TestCopyResourceFile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "/src.txt", []byte("hello"), 0664)
+
+	if err := copyResource(fs, "/src.txt", "/dst.txt"); err != nil {
+		t.Fatalf("copyResource() error = %v", err)
+	}
+
+	if ok, _ := afero.Exists(fs, "/dst.txt"); !ok {
+		t.Fatal("expected /dst.txt to exist")
+	}
+}
+
+func TestCopyResourceFileCleansUpDestinationOnError(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "/src.txt", []byte("hello"), 0664)
+
+	// Make the destination's parent a regular file rather than a
+	// directory, so copyFile's MkdirAll fails after copyResource has
+	// already decided dst needs cleaning up on error.
+	afero.WriteFile(fs, "/blocked", []byte("x"), 0664)
+
+	err := copyResource(fs, "/src.txt", "/blocked/dst.txt")
+	if err == nil {
+		t.Fatal("expected copyResource to fail when destination directory cannot be created")
+	}
+
+	if ok, _ := afero.Exists(fs, "/blocked/dst.txt"); ok {
+		t.Fatal("expected partial destination to be cleaned up after failure")
+	}
+}
+
+func TestCopyResourceDirectoryCleansUpOnError(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "/src/a.txt", []byte("a"), 0664)
+	afero.WriteFile(fs, "/src/b.txt", []byte("b"), 0664)
+
+	// Pre-create the destination for the second file as a directory, so
+	// copyFile's OpenFile on it fails partway through the walk.
+	if err := fs.MkdirAll("/dst/b.txt", 0775); err != nil {
+		t.Fatal(err)
+	}
+
+	err := copyResource(fs, "/src", "/dst")
+	if err == nil {
+		t.Fatal("expected copyResource to fail when a file within the tree cannot be copied")
+	}
+
+	if ok, _ := afero.Exists(fs, "/dst"); ok {
+		t.Fatal("expected the whole partially-copied destination tree to be removed after failure")
+	}
+}
+
+func TestFileEtagChangesWithModTimeAndSize(t *testing.T) {
+	now := time.Now()
+
+	a := fakeFileInfo{size: 5, modTime: now}
+	b := fakeFileInfo{size: 5, modTime: now.Add(time.Second)}
+	c := fakeFileInfo{size: 6, modTime: now}
+
+	if fileEtag(a) == fileEtag(b) {
+		t.Fatal("expected ETag to change when ModTime changes")
+	}
+	if fileEtag(a) == fileEtag(c) {
+		t.Fatal("expected ETag to change when Size changes")
+	}
+	if fileEtag(a) != fileEtag(a) {
+		t.Fatal("expected ETag to be stable for the same FileInfo")
+	}
+}
+
+func TestWriteResourceConflict(t *testing.T) {
+	w := httptest.NewRecorder()
+	info := fakeFileInfo{size: 42, modTime: time.Now()}
+
+	writeResourceConflict(w, "/some/path", info)
+
+	if w.Code != 409 {
+		t.Fatalf("status = %d, want 409", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", ct)
+	}
+}
+
+type fakeFileInfo struct {
+	size    int64
+	modTime time.Time
+}
+
+func (f fakeFileInfo) Name() string       { return "fake" }
+func (f fakeFileInfo) Size() int64        { return f.size }
+func (f fakeFileInfo) Mode() os.FileMode  { return 0664 }
+func (f fakeFileInfo) ModTime() time.Time { return f.modTime }
+func (f fakeFileInfo) IsDir() bool        { return false }
+func (f fakeFileInfo) Sys() interface{}   { return nil }
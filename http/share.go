@@ -3,33 +3,86 @@ package http
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"io"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/filebrowser/filebrowser/types"
 )
 
+// keyedMutex hands out a lock per string key, so unrelated share hashes
+// never contend with one another while same-hash requests serialize.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{locks: map[string]*sync.Mutex{}}
+}
+
+func (k *keyedMutex) Lock(key string) (unlock func()) {
+	k.mu.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		k.locks[key] = l
+	}
+	k.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// shareLocks serializes the read-check-increment-save sequence a share
+// link's download count goes through, so concurrent requests against
+// the same link can't both pass a stale download-limit check.
+var shareLocks = newKeyedMutex()
+
 const apiSharePrefix = "/api/share"
 
-func (e *Env) getShareData(w http.ResponseWriter, r *http.Request, prefix string) (string, bool) {
+// publicSharePrefix is where anonymous visitors hit a share link,
+// e.g. GET /share/{hash} or, in upload mode, POST /share/{hash}/{name}.
+const publicSharePrefix = "/share"
+
+// publicShareHash splits a public share request's path into the link's
+// hash and, for upload mode, the remainder used as the destination
+// filename.
+func publicShareHash(r *http.Request) (hash string, rest string) {
+	trimmed := strings.TrimPrefix(r.URL.Path, publicSharePrefix)
+	trimmed = strings.TrimPrefix(trimmed, "/")
+	trimmed = strings.TrimSuffix(trimmed, "/")
+
+	parts := strings.SplitN(trimmed, "/", 2)
+	hash = parts[0]
+	if len(parts) == 2 {
+		rest = parts[1]
+	}
+
+	return hash, rest
+}
+
+func (e *Env) getShareData(w http.ResponseWriter, r *http.Request, prefix string) (string, *types.User, bool) {
 	relPath, user, ok := e.getResourceData(w, r, apiSharePrefix)
 	if !ok {
-		return "", false
+		return "", nil, false
 	}
 
 	if !user.Perm.Share {
 		httpErr(w, http.StatusForbidden, nil)
-		return "", false
+		return "", nil, false
 	}
 
-	return filepath.Join(user.Scope, relPath), ok
+	return filepath.Join(user.Scope, relPath), user, ok
 }
 
 func (e *Env) shareGetHandler(w http.ResponseWriter, r *http.Request) {
-	path, ok := e.getShareData(w, r, apiSharePrefix)
+	path, _, ok := e.getShareData(w, r, apiSharePrefix)
 	if !ok {
 		return
 	}
@@ -55,6 +108,13 @@ func (e *Env) shareGetHandler(w http.ResponseWriter, r *http.Request) {
 	renderJSON(w, s)
 }
 
+func shareHash(r *http.Request) string {
+	hash := strings.TrimPrefix(r.URL.Path, apiSharePrefix)
+	hash = strings.TrimSuffix(hash, "/")
+	hash = strings.TrimPrefix(hash, "/")
+	return hash
+}
+
 func (e *Env) shareDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	user, ok := e.getUser(w, r)
 	if !ok {
@@ -66,31 +126,138 @@ func (e *Env) shareDeleteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hash := strings.TrimPrefix(r.URL.Path, apiSharePrefix)
-	hash = strings.TrimSuffix(hash, "/")
-	hash = strings.TrimPrefix(hash, "/")
+	hash := shareHash(r)
 	if hash == "" {
 		return
 	}
 
-	err := e.Store.Share.Delete(hash)
-	if err != nil {
+	s, err := e.Store.Share.GetByHash(hash)
+	if err == types.ErrNotExist {
+		httpErr(w, http.StatusNotFound, nil)
+		return
+	} else if err != nil {
+		httpErr(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if s.UserID != user.ID && !user.Perm.Admin {
+		httpErr(w, http.StatusForbidden, nil)
+		return
+	}
+
+	if err := e.Store.Share.Delete(hash); err != nil {
 		httpErr(w, http.StatusInternalServerError, err)
 		return
 	}
 }
 
+// sharePatchHandler rotates the password, adjusts the expiry, or resets
+// the download counter of an existing share link without deleting and
+// recreating it.
+func (e *Env) sharePatchHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := e.getUser(w, r)
+	if !ok {
+		return
+	}
+
+	if !user.Perm.Share {
+		httpErr(w, http.StatusForbidden, nil)
+		return
+	}
+
+	hash := shareHash(r)
+	if hash == "" {
+		httpErr(w, http.StatusBadRequest, nil)
+		return
+	}
+
+	s, err := e.Store.Share.GetByHash(hash)
+	if err == types.ErrNotExist {
+		httpErr(w, http.StatusNotFound, nil)
+		return
+	} else if err != nil {
+		httpErr(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if s.UserID != user.ID && !user.Perm.Admin {
+		httpErr(w, http.StatusForbidden, nil)
+		return
+	}
+
+	query := r.URL.Query()
+
+	if password := query.Get("password"); password != "" {
+		hash, err := types.HashPwd(password)
+		if err != nil {
+			httpErr(w, http.StatusInternalServerError, err)
+			return
+		}
+		s.PasswordHash = hash
+	} else if query.Get("removePassword") == "true" {
+		s.PasswordHash = ""
+	}
+
+	if expire := query.Get("expires"); expire != "" {
+		add, err := parseShareDuration(expire, query.Get("unit"))
+		if err != nil {
+			httpErr(w, http.StatusBadRequest, err)
+			return
+		}
+		s.Expires = true
+		s.ExpireDate = time.Now().Add(add)
+	} else if query.Get("removeExpiry") == "true" {
+		s.Expires = false
+	}
+
+	if max := query.Get("maxDownloads"); max != "" {
+		num, err := strconv.Atoi(max)
+		if err != nil {
+			httpErr(w, http.StatusBadRequest, err)
+			return
+		}
+		s.MaxDownloads = num
+	}
+
+	if query.Get("resetDownloads") == "true" {
+		s.Downloads = 0
+	}
+
+	if err := e.Store.Share.Save(s); err != nil {
+		httpErr(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	renderJSON(w, s)
+}
+
 func (e *Env) sharePostHandler(w http.ResponseWriter, r *http.Request) {
-	path, ok := e.getShareData(w, r, apiSharePrefix)
+	path, user, ok := e.getShareData(w, r, apiSharePrefix)
 	if !ok {
 		return
 	}
 
+	mode := types.ShareMode(r.URL.Query().Get("mode"))
+	switch mode {
+	case "", types.ShareModeDownload:
+		mode = types.ShareModeDownload
+	case types.ShareModeUpload, types.ShareModeBoth:
+		if !user.Perm.Create {
+			httpErr(w, http.StatusForbidden, nil)
+			return
+		}
+	default:
+		httpErr(w, http.StatusBadRequest, nil)
+		return
+	}
+
 	var s *types.ShareLink
 	expire := r.URL.Query().Get("expires")
 	unit := r.URL.Query().Get("unit")
+	password := r.URL.Query().Get("password")
+	maxDownloads := r.URL.Query().Get("maxDownloads")
 
-	if expire == "" {
+	if expire == "" && password == "" && maxDownloads == "" && mode == types.ShareModeDownload {
 		var err error
 		s, err = e.Store.Share.GetPermanent(path)
 		if err == nil {
@@ -111,29 +278,57 @@ func (e *Env) sharePostHandler(w http.ResponseWriter, r *http.Request) {
 	s = &types.ShareLink{
 		Path:    path,
 		Hash:    str,
+		UserID:  user.ID,
+		Mode:    mode,
 		Expires: expire != "",
 	}
 
+	if mode != types.ShareModeDownload {
+		if maxSize := r.URL.Query().Get("maxUploadSize"); maxSize != "" {
+			s.MaxUploadSize, err = strconv.ParseInt(maxSize, 10, 64)
+			if err != nil {
+				httpErr(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		if maxTotal := r.URL.Query().Get("maxTotalBytes"); maxTotal != "" {
+			s.MaxTotalBytes, err = strconv.ParseInt(maxTotal, 10, 64)
+			if err != nil {
+				httpErr(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		if mimes := r.URL.Query().Get("allowedMimePrefixes"); mimes != "" {
+			s.AllowedMimePrefixes = strings.Split(mimes, ",")
+		}
+	}
+
 	if expire != "" {
-		num, err := strconv.Atoi(expire)
+		add, err := parseShareDuration(expire, unit)
 		if err != nil {
 			httpErr(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		var add time.Duration
-		switch unit {
-		case "seconds":
-			add = time.Second * time.Duration(num)
-		case "minutes":
-			add = time.Minute * time.Duration(num)
-		case "days":
-			add = time.Hour * 24 * time.Duration(num)
-		default:
-			add = time.Hour * time.Duration(num)
+		s.ExpireDate = time.Now().Add(add)
+	}
+
+	if password != "" {
+		s.PasswordHash, err = types.HashPwd(password)
+		if err != nil {
+			httpErr(w, http.StatusInternalServerError, err)
+			return
 		}
+	}
 
-		s.ExpireDate = time.Now().Add(add)
+	if maxDownloads != "" {
+		s.MaxDownloads, err = strconv.Atoi(maxDownloads)
+		if err != nil {
+			httpErr(w, http.StatusInternalServerError, err)
+			return
+		}
 	}
 
 	if err := e.Store.Share.Save(s); err != nil {
@@ -142,4 +337,224 @@ func (e *Env) sharePostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	renderJSON(w, s)
+}
+
+// parseShareDuration converts the "expires"/"unit" query params used by
+// sharePostHandler and sharePatchHandler into a time.Duration.
+func parseShareDuration(expire, unit string) (time.Duration, error) {
+	num, err := strconv.Atoi(expire)
+	if err != nil {
+		return 0, err
+	}
+
+	switch unit {
+	case "seconds":
+		return time.Second * time.Duration(num), nil
+	case "minutes":
+		return time.Minute * time.Duration(num), nil
+	case "days":
+		return time.Hour * 24 * time.Duration(num), nil
+	default:
+		return time.Hour * time.Duration(num), nil
+	}
+}
+
+// publicShareHandler serves the file behind a share link to an
+// unauthenticated visitor. It enforces the link's password (if any),
+// its expiry, and its download limit, atomically incrementing the
+// download counter and deleting the link once the limit is reached.
+func (e *Env) publicShareHandler(w http.ResponseWriter, r *http.Request) {
+	hash, _ := publicShareHash(r)
+
+	// The whole read-check-increment-save sequence below has to run as
+	// one atomic unit per hash, or two concurrent requests against a
+	// link with e.g. MaxDownloads=1 can both pass the limit check
+	// before either one saves.
+	unlock := shareLocks.Lock(hash)
+	defer unlock()
+
+	s, err := e.Store.Share.GetByHash(hash)
+	if err == types.ErrNotExist {
+		httpErr(w, http.StatusNotFound, nil)
+		return
+	} else if err != nil {
+		httpErr(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if s.Expires && s.ExpireDate.Before(time.Now()) {
+		e.Store.Share.Delete(s.Hash)
+		httpErr(w, http.StatusNotFound, nil)
+		return
+	}
+
+	if !s.AllowsDownload() {
+		httpErr(w, http.StatusForbidden, nil)
+		return
+	}
+
+	if s.ReachedDownloadLimit() {
+		e.Store.Share.Delete(s.Hash)
+		httpErr(w, http.StatusNotFound, nil)
+		return
+	}
+
+	if s.RequiresPassword() && !types.CheckPwd(r.URL.Query().Get("password"), s.PasswordHash) {
+		httpErr(w, http.StatusUnauthorized, nil)
+		return
+	}
+
+	f, err := os.Open(s.Path)
+	if err != nil {
+		httpErr(w, httpFsErr(err), err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		httpErr(w, httpFsErr(err), err)
+		return
+	}
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+
+	s.Downloads++
+	if s.ReachedDownloadLimit() {
+		e.Store.Share.Delete(s.Hash)
+		return
+	}
+
+	e.Store.Share.Save(s)
+}
+
+// shareUploadLimit computes the effective byte limit for a single upload
+// given the share's configured per-file and total-bytes caps plus what
+// has already been uploaded. hasLimit is false when neither cap is
+// configured, in which case limit and withinLimit are meaningless.
+// Otherwise withinLimit reports whether any bytes may still be accepted.
+func shareUploadLimit(maxUploadSize, maxTotalBytes, uploadedBytes int64) (limit int64, hasLimit bool, withinLimit bool) {
+	hasLimit = maxUploadSize > 0 || maxTotalBytes > 0
+	if !hasLimit {
+		return 0, false, false
+	}
+
+	limit = maxUploadSize
+
+	if maxTotalBytes > 0 {
+		remaining := maxTotalBytes - uploadedBytes
+		if remaining < 0 {
+			remaining = 0
+		}
+		if limit == 0 || remaining < limit {
+			limit = remaining
+		}
+	}
+
+	return limit, true, limit > 0
+}
+
+// publicShareUploadHandler lets an anonymous visitor drop a file into a
+// share configured with Mode upload or both, acting as the share
+// owner's user. Only POST is ever routed here, so this is the only way
+// in through a share link's upload side — there is no PUT, DELETE or
+// PATCH equivalent regardless of what a client sends as query params.
+func (e *Env) publicShareUploadHandler(w http.ResponseWriter, r *http.Request) {
+	hash, name := publicShareHash(r)
+	if name == "" {
+		httpErr(w, http.StatusBadRequest, nil)
+		return
+	}
+
+	s, err := e.Store.Share.GetByHash(hash)
+	if err == types.ErrNotExist {
+		httpErr(w, http.StatusNotFound, nil)
+		return
+	} else if err != nil {
+		httpErr(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if s.Expires && s.ExpireDate.Before(time.Now()) {
+		e.Store.Share.Delete(s.Hash)
+		httpErr(w, http.StatusNotFound, nil)
+		return
+	}
+
+	if !s.AllowsUpload() {
+		httpErr(w, http.StatusForbidden, nil)
+		return
+	}
+
+	if s.RequiresPassword() && !types.CheckPwd(r.URL.Query().Get("password"), s.PasswordHash) {
+		httpErr(w, http.StatusUnauthorized, nil)
+		return
+	}
+
+	// The destination is always forced under the share's own directory,
+	// however the visitor phrases the filename.
+	dest := filepath.Join(s.Path, filepath.Join("/", name))
+	if dest != s.Path && !strings.HasPrefix(dest, s.Path+string(filepath.Separator)) {
+		httpErr(w, http.StatusForbidden, nil)
+		return
+	}
+
+	if !s.AllowsMime(r.Header.Get("Content-Type")) {
+		httpErr(w, http.StatusUnsupportedMediaType, nil)
+		return
+	}
+
+	// Content-Length is attacker-supplied (and absent on chunked
+	// requests), so it can't be trusted as the enforcement point. Cap
+	// the body we actually read instead, leaving one extra byte of
+	// headroom so we can tell "exactly at the limit" apart from "over
+	// it" once the copy is done.
+	var body io.Reader = r.Body
+	limit, hasLimit, withinLimit := shareUploadLimit(s.MaxUploadSize, s.MaxTotalBytes, s.UploadedBytes)
+
+	if hasLimit {
+		if !withinLimit {
+			httpErr(w, http.StatusRequestEntityTooLarge, nil)
+			return
+		}
+
+		body = io.LimitReader(r.Body, limit+1)
+	}
+
+	owner, err := e.Store.Users.Get(s.UserID)
+	if err != nil {
+		httpErr(w, http.StatusInternalServerError, err)
+		return
+	}
+	owner.BuildFs()
+
+	relDest := strings.TrimPrefix(dest, owner.Scope)
+
+	var written int64
+	err = e.Runner.Run(func() error {
+		file, err := owner.Fs.OpenFile(relDest, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0664)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		written, err = io.Copy(file, body)
+		return err
+	}, "upload-share", relDest, "", owner)
+
+	if err == nil && hasLimit && written > limit {
+		owner.Fs.Remove(relDest)
+		httpErr(w, http.StatusRequestEntityTooLarge, nil)
+		return
+	}
+
+	if err != nil {
+		httpErr(w, httpFsErr(err), err)
+		return
+	}
+
+	s.UploadedBytes += written
+	e.Store.Share.Save(s)
+
+	w.WriteHeader(http.StatusCreated)
 }
\ No newline at end of file
@@ -0,0 +1,39 @@
+package http
+
+import "testing"
+
+func TestShareUploadLimit(t *testing.T) {
+	cases := []struct {
+		name                     string
+		maxUploadSize, maxTotal  int64
+		uploaded                 int64
+		wantHasLimit, wantWithin bool
+		wantLimit                int64
+	}{
+		{"no limits configured", 0, 0, 0, false, false, 0},
+		{"per-file limit only", 100, 0, 0, true, true, 100},
+		{"total bytes limit only", 0, 100, 40, true, true, 60},
+		{"total bytes already exhausted", 0, 100, 100, true, false, 0},
+		{"total bytes over-exhausted", 0, 100, 150, true, false, 0},
+		{"per-file limit smaller than remaining total", 50, 100, 40, true, true, 50},
+		{"remaining total smaller than per-file limit", 1000, 100, 40, true, true, 60},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			limit, hasLimit, withinLimit := shareUploadLimit(c.maxUploadSize, c.maxTotal, c.uploaded)
+			if hasLimit != c.wantHasLimit {
+				t.Fatalf("hasLimit = %v, want %v", hasLimit, c.wantHasLimit)
+			}
+			if !hasLimit {
+				return
+			}
+			if withinLimit != c.wantWithin {
+				t.Fatalf("withinLimit = %v, want %v", withinLimit, c.wantWithin)
+			}
+			if withinLimit && limit != c.wantLimit {
+				t.Fatalf("limit = %d, want %d", limit, c.wantLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/filebrowser/filebrowser/types"
+)
+
+const apiTusPrefix = "/api/tus"
+
+// tusUploadTTL is how long an upload can sit idle before it is eligible
+// for cleanup.
+const tusUploadTTL = 24 * time.Hour
+
+// parseTusMetadata parses the Upload-Metadata header, a comma-separated
+// list of "key base64value" pairs, as defined by the tus.io protocol.
+func parseTusMetadata(header string) map[string]string {
+	meta := map[string]string{}
+	if header == "" {
+		return meta
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, " ", 2)
+		key := parts[0]
+
+		var value string
+		if len(parts) == 2 {
+			if decoded, err := base64.StdEncoding.DecodeString(parts[1]); err == nil {
+				value = string(decoded)
+			}
+		}
+
+		meta[key] = value
+	}
+
+	return meta
+}
+
+func tusID(r *http.Request) string {
+	id := strings.TrimPrefix(r.URL.Path, apiTusPrefix)
+	id = strings.TrimPrefix(id, "/")
+	id = strings.TrimSuffix(id, "/")
+	return id
+}
+
+// tusPostHandler creates a new resumable upload. It mirrors the
+// permission checks of resourcePostPutHandler, deciding between Create
+// and Modify depending on whether the destination already exists.
+func (e *Env) tusPostHandler(w http.ResponseWriter, r *http.Request) {
+	path, user, ok := e.getResourceData(w, r, apiTusPrefix)
+	if !ok {
+		return
+	}
+
+	_, err := user.Fs.Stat(path)
+	exists := err == nil
+
+	if exists && !user.Perm.Modify {
+		httpErr(w, http.StatusForbidden, nil)
+		return
+	}
+	if !exists && !user.Perm.Create {
+		httpErr(w, http.StatusForbidden, nil)
+		return
+	}
+
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		httpErr(w, http.StatusBadRequest, nil)
+		return
+	}
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		httpErr(w, http.StatusInternalServerError, err)
+		return
+	}
+	id := base64.URLEncoding.EncodeToString(idBytes)
+
+	tempPath := filepath.Join(".tus-uploads", id)
+	if err := user.Fs.MkdirAll(filepath.Dir(tempPath), 0775); err != nil {
+		httpErr(w, httpFsErr(err), err)
+		return
+	}
+
+	f, err := user.Fs.OpenFile(tempPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0664)
+	if err != nil {
+		httpErr(w, httpFsErr(err), err)
+		return
+	}
+	f.Close()
+
+	upload := &types.TusUpload{
+		ID:       id,
+		Path:     path,
+		Offset:   0,
+		Size:     size,
+		UserID:   user.ID,
+		Metadata: parseTusMetadata(r.Header.Get("Upload-Metadata")),
+		TempPath: tempPath,
+		Expires:  time.Now().Add(tusUploadTTL),
+	}
+
+	if err := e.Store.TusUpload.Save(upload); err != nil {
+		httpErr(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Location", e.Settings.BaseURL+apiTusPrefix+"/"+id)
+	w.Header().Set("Tus-Resumable", "1.0.0")
+	w.WriteHeader(http.StatusCreated)
+}
+
+// expireTusUpload removes the temp file backing an upload and drops its
+// store record. Errors are ignored on a best-effort basis: whichever
+// half fails, the upload is being torn down either way.
+func (e *Env) expireTusUpload(user *types.User, upload *types.TusUpload) {
+	user.Fs.Remove(upload.TempPath)
+	e.Store.TusUpload.Delete(upload.ID)
+}
+
+// tusHeadHandler reports the current offset of an in-progress upload so
+// a client can resume after a dropped connection.
+func (e *Env) tusHeadHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := e.getUser(w, r)
+	if !ok {
+		return
+	}
+
+	upload, err := e.Store.TusUpload.GetByID(tusID(r))
+	if err == types.ErrNotExist {
+		httpErr(w, http.StatusNotFound, nil)
+		return
+	} else if err != nil {
+		httpErr(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if upload.UserID != user.ID {
+		httpErr(w, http.StatusForbidden, nil)
+		return
+	}
+
+	if time.Now().After(upload.Expires) {
+		e.expireTusUpload(user, upload)
+		httpErr(w, http.StatusNotFound, nil)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Tus-Resumable", "1.0.0")
+	w.WriteHeader(http.StatusOK)
+}
+
+// tusPatchHandler appends bytes to an in-progress upload and, once the
+// full size has been received, atomically moves the temp file into
+// place under the user's filesystem.
+func (e *Env) tusPatchHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := e.getUser(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		httpErr(w, http.StatusUnsupportedMediaType, nil)
+		return
+	}
+
+	upload, err := e.Store.TusUpload.GetByID(tusID(r))
+	if err == types.ErrNotExist {
+		httpErr(w, http.StatusNotFound, nil)
+		return
+	} else if err != nil {
+		httpErr(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if upload.UserID != user.ID {
+		httpErr(w, http.StatusForbidden, nil)
+		return
+	}
+
+	if time.Now().After(upload.Expires) {
+		e.expireTusUpload(user, upload)
+		httpErr(w, http.StatusNotFound, nil)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset != upload.Offset {
+		httpErr(w, http.StatusConflict, nil)
+		return
+	}
+
+	f, err := user.Fs.OpenFile(upload.TempPath, os.O_RDWR|os.O_APPEND, 0664)
+	if err != nil {
+		httpErr(w, httpFsErr(err), err)
+		return
+	}
+
+	// Cap the read at exactly one byte past what Upload-Length allows.
+	// A client that declares a small Size and then streams past it
+	// would otherwise grow the temp file unboundedly before the size
+	// check below ever runs, wedging the upload with no way back to a
+	// valid offset.
+	remaining := upload.Size - upload.Offset
+	_, copyErr := io.Copy(f, io.LimitReader(r.Body, remaining+1))
+
+	// Reconcile the stored offset with what actually landed on disk
+	// before reporting any error, even a partial one: O_APPEND writes
+	// whatever it can before failing, and the client will retry with
+	// the offset we return here, not the byte count we intended to
+	// write.
+	stat, statErr := f.Stat()
+	if statErr != nil {
+		f.Close()
+		httpErr(w, httpFsErr(statErr), statErr)
+		return
+	}
+
+	if stat.Size() > upload.Size {
+		// The client sent more than it declared. Truncate back to the
+		// last valid offset so the upload stays resumable instead of
+		// being permanently wedged, then reject this attempt.
+		f.Truncate(upload.Size)
+		f.Close()
+
+		upload.Offset = upload.Size
+		e.Store.TusUpload.Save(upload)
+		httpErr(w, http.StatusBadRequest, nil)
+		return
+	}
+	f.Close()
+
+	upload.Offset = stat.Size()
+	upload.Expires = time.Now().Add(tusUploadTTL)
+	if err := e.Store.TusUpload.Save(upload); err != nil {
+		httpErr(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if copyErr != nil {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		httpErr(w, http.StatusInternalServerError, copyErr)
+		return
+	}
+
+	if upload.Offset < upload.Size {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		w.Header().Set("Tus-Resumable", "1.0.0")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Upload complete: move the temp file into place with the same
+	// permission checks resourcePostPutHandler applies, then drop the
+	// upload record.
+	_, destStatErr := user.Fs.Stat(upload.Path)
+	destExists := destStatErr == nil
+
+	if destExists && !user.Perm.Modify {
+		httpErr(w, http.StatusForbidden, nil)
+		return
+	}
+	if !destExists && !user.Perm.Create {
+		httpErr(w, http.StatusForbidden, nil)
+		return
+	}
+
+	err = e.Runner.Run(func() error {
+		return user.Fs.Rename(upload.TempPath, upload.Path)
+	}, "upload", upload.Path, "", user)
+
+	if err != nil {
+		httpErr(w, httpFsErr(err), err)
+		return
+	}
+
+	if err := e.Store.TusUpload.Delete(upload.ID); err != nil {
+		httpErr(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Tus-Resumable", "1.0.0")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,82 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// ShareMode controls whether visitors to a share link can download the
+// shared resource, upload into it, or both.
+type ShareMode string
+
+const (
+	ShareModeDownload ShareMode = "download"
+	ShareModeUpload   ShareMode = "upload"
+	ShareModeBoth     ShareMode = "both"
+)
+
+// ShareLink is a link to a file or directory that can optionally expire,
+// require a password, or be limited to a maximum number of downloads.
+// In upload mode it also acts as a "drop box", letting anonymous
+// visitors submit files into Path as the owner identified by UserID.
+type ShareLink struct {
+	Path         string    `storm:"index" json:"path"`
+	Hash         string    `storm:"id" json:"hash"`
+	UserID       uint      `storm:"index" json:"userId"`
+	Expires      bool      `json:"expires"`
+	ExpireDate   time.Time `json:"expireDate"`
+	PasswordHash string    `json:"-"`
+	Downloads    int       `json:"downloads"`
+	MaxDownloads int       `json:"maxDownloads"`
+
+	Mode ShareMode `json:"mode"`
+
+	// Upload-mode limits. Zero means unlimited.
+	MaxUploadSize       int64    `json:"maxUploadSize"`
+	AllowedMimePrefixes []string `json:"allowedMimePrefixes"`
+	MaxTotalBytes       int64    `json:"maxTotalBytes"`
+
+	// UploadedBytes is the running total received through the link,
+	// counted against MaxTotalBytes.
+	UploadedBytes int64 `json:"uploadedBytes"`
+}
+
+// RequiresPassword tells whether the share link is password-protected.
+func (s *ShareLink) RequiresPassword() bool {
+	return s.PasswordHash != ""
+}
+
+// ReachedDownloadLimit tells whether the share link has hit its
+// configured maximum number of downloads.
+func (s *ShareLink) ReachedDownloadLimit() bool {
+	return s.MaxDownloads > 0 && s.Downloads >= s.MaxDownloads
+}
+
+// AllowsDownload tells whether the share link's mode permits serving
+// its resource to visitors.
+func (s *ShareLink) AllowsDownload() bool {
+	return s.Mode == "" || s.Mode == ShareModeDownload || s.Mode == ShareModeBoth
+}
+
+// AllowsUpload tells whether the share link's mode permits visitors to
+// drop files into its directory.
+func (s *ShareLink) AllowsUpload() bool {
+	return s.Mode == ShareModeUpload || s.Mode == ShareModeBoth
+}
+
+// AllowsMime tells whether contentType is permitted by the share
+// link's configured MIME prefixes. An empty prefix list allows
+// anything.
+func (s *ShareLink) AllowsMime(contentType string) bool {
+	if len(s.AllowedMimePrefixes) == 0 {
+		return true
+	}
+
+	for _, prefix := range s.AllowedMimePrefixes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
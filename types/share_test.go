@@ -0,0 +1,84 @@
+package types
+
+import "testing"
+
+func TestShareLinkRequiresPassword(t *testing.T) {
+	s := &ShareLink{}
+	if s.RequiresPassword() {
+		t.Fatal("expected a share with no PasswordHash to not require a password")
+	}
+
+	s.PasswordHash = "$2a$10$somehash"
+	if !s.RequiresPassword() {
+		t.Fatal("expected a share with a PasswordHash to require a password")
+	}
+}
+
+func TestShareLinkReachedDownloadLimit(t *testing.T) {
+	cases := []struct {
+		name         string
+		maxDownloads int
+		downloads    int
+		want         bool
+	}{
+		{"unlimited", 0, 1000, false},
+		{"under limit", 3, 2, false},
+		{"at limit", 3, 3, true},
+		{"over limit", 3, 4, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := &ShareLink{MaxDownloads: c.maxDownloads, Downloads: c.downloads}
+			if got := s.ReachedDownloadLimit(); got != c.want {
+				t.Fatalf("ReachedDownloadLimit() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestShareLinkAllowsDownloadAndUpload(t *testing.T) {
+	cases := []struct {
+		mode         ShareMode
+		wantDownload bool
+		wantUpload   bool
+	}{
+		{"", true, false},
+		{ShareModeDownload, true, false},
+		{ShareModeUpload, false, true},
+		{ShareModeBoth, true, true},
+	}
+
+	for _, c := range cases {
+		s := &ShareLink{Mode: c.mode}
+		if got := s.AllowsDownload(); got != c.wantDownload {
+			t.Errorf("mode %q: AllowsDownload() = %v, want %v", c.mode, got, c.wantDownload)
+		}
+		if got := s.AllowsUpload(); got != c.wantUpload {
+			t.Errorf("mode %q: AllowsUpload() = %v, want %v", c.mode, got, c.wantUpload)
+		}
+	}
+}
+
+func TestShareLinkAllowsMime(t *testing.T) {
+	cases := []struct {
+		name     string
+		prefixes []string
+		mime     string
+		want     bool
+	}{
+		{"no restriction", nil, "application/octet-stream", true},
+		{"matching prefix", []string{"image/", "video/"}, "image/png", true},
+		{"non-matching prefix", []string{"image/", "video/"}, "application/pdf", false},
+		{"exact prefix match", []string{"text/plain"}, "text/plain", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := &ShareLink{AllowedMimePrefixes: c.prefixes}
+			if got := s.AllowsMime(c.mime); got != c.want {
+				t.Fatalf("AllowsMime(%q) = %v, want %v", c.mime, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package types
+
+import "time"
+
+// TusUpload represents the state of an in-progress resumable upload
+// created through the tus.io protocol. It is persisted so that HEAD
+// requests can report the current offset even across server restarts.
+type TusUpload struct {
+	ID       string            `storm:"id" json:"id"`
+	Path     string            `json:"path"`
+	Offset   int64             `json:"offset"`
+	Size     int64             `json:"size"`
+	UserID   uint              `storm:"index" json:"userId"`
+	Metadata map[string]string `json:"metadata"`
+	TempPath string            `json:"tempPath"`
+	Expires  time.Time         `json:"expires"`
+}